@@ -21,6 +21,8 @@ import (
 	"io"
 	"net/url"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/meta"
@@ -60,6 +62,22 @@ type Builder struct {
 
 	singleResourceType bool
 	continueOnError    bool
+
+	clusterMappers map[string]*Mapper
+	clusterNames   []string
+
+	jpath         []string
+	jsonnetTLA    map[string]string
+	jsonnetExtVar map[string]string
+
+	recursive         bool
+	fileExtensions    []string
+	includeHiddenDirs bool
+
+	ociRefs []string
+
+	pageSize          int
+	disablePagination bool
 }
 
 type resourceTuple struct {
@@ -74,6 +92,31 @@ func NewBuilder(mapper meta.RESTMapper, typer runtime.ObjectTyper, clientMapper
 	}
 }
 
+// ClusterHandle describes how to reach a single cluster - the RESTMapper and
+// ObjectTyper used to interpret resources found there, and the ClientMapper
+// used to construct clients against it.
+type ClusterHandle struct {
+	Mapper       meta.RESTMapper
+	Typer        runtime.ObjectTyper
+	ClientMapper ClientMapper
+}
+
+// Clusters instructs the builder to fan out the server-side branches of resolution -
+// selectors, resource/name tuples, and bare names - across the given named clusters,
+// instead of the single cluster passed to NewBuilder. The resulting Infos are tagged
+// with their source cluster name so Result.Visit and Result.Infos can tell them
+// apart, and ContinueOnError (if set) applies independently to each cluster. File,
+// URL, and OCI inputs cannot be combined with Clusters; Do() then returns an error.
+func (b *Builder) Clusters(mappers map[string]ClusterHandle) *Builder {
+	b.clusterMappers = make(map[string]*Mapper, len(mappers))
+	for name, handle := range mappers {
+		b.clusterMappers[name] = &Mapper{handle.Typer, handle.Mapper, handle.ClientMapper}
+		b.clusterNames = append(b.clusterNames, name)
+	}
+	sort.Strings(b.clusterNames)
+	return b
+}
+
 // Filename is parameters passed via a filename argument which may be URLs, the "-" argument indicating
 // STDIN, or paths to files or directories. If ContinueOnError() is set prior to this method being called,
 // objects on the path that are unrecognized will be ignored (but logged at V(2)).
@@ -89,6 +132,8 @@ func (b *Builder) FilenameParam(paths ...string) *Builder {
 				continue
 			}
 			b.URL(url)
+		case strings.Index(s, "oci://") == 0:
+			b.OCIRef(strings.TrimPrefix(s, "oci://"))
 		default:
 			b.Path(s)
 		}
@@ -96,9 +141,14 @@ func (b *Builder) FilenameParam(paths ...string) *Builder {
 	return b
 }
 
-// URL accepts a number of URLs directly.
+// URL accepts a number of URLs directly. A URL ending in .jsonnet or .libsonnet is
+// fetched and evaluated as jsonnet rather than decoded directly as YAML/JSON.
 func (b *Builder) URL(urls ...*url.URL) *Builder {
 	for _, u := range urls {
+		if isJsonnet(u.Path) {
+			b.paths = append(b.paths, b.newJsonnetVisitor(u.String()))
+			continue
+		}
 		b.paths = append(b.paths, &URLVisitor{
 			Mapper: b.mapper,
 			URL:    u,
@@ -107,6 +157,24 @@ func (b *Builder) URL(urls ...*url.URL) *Builder {
 	return b
 }
 
+// OCIRef accepts a number of references to manifests packaged as OCI artifacts in a
+// container registry (for example "registry.example.com/team/app-manifests:v1.2.3").
+// If the reference resolves to an index/referrers list rather than a single
+// manifest, every artifact it lists is fetched and visited. A tag reference (as
+// opposed to a digest) is re-resolved against the registry every time the returned
+// Visitor is visited, so Do() always sees the current contents of a mutable tag.
+func (b *Builder) OCIRef(refs ...string) *Builder {
+	for _, ref := range refs {
+		b.ociRefs = append(b.ociRefs, ref)
+		b.paths = append(b.paths, &OCIVisitor{
+			Mapper:       b.mapper,
+			Ref:          ref,
+			IgnoreErrors: b.continueOnError,
+		})
+	}
+	return b
+}
+
 // Stdin will read objects from the standard input. If ContinueOnError() is set
 // prior to this method being called, objects in the stream that are unrecognized
 // will be ignored (but logged at V(2)).
@@ -125,10 +193,31 @@ func (b *Builder) Stream(r io.Reader, name string) *Builder {
 }
 
 // Path is a set of filesystem paths that may be files containing one or more
-// resources. If ContinueOnError() is set prior to this method being called,
-// objects on the path that are unrecognized will be ignored (but logged at V(2)).
+// resources, directories, or glob patterns (including a "**" segment to match
+// directories at any depth). If ContinueOnError() is set prior to this method
+// being called, objects on the path that are unrecognized will be ignored (but
+// logged at V(2)); files read from a directory and objects produced by a .jsonnet
+// or .libsonnet file are skipped without being logged.
 func (b *Builder) Path(paths ...string) *Builder {
+	expanded := []string{}
 	for _, p := range paths {
+		if !strings.ContainsAny(p, "*?[") {
+			expanded = append(expanded, p)
+			continue
+		}
+		matches, err := globPaths(p)
+		if err != nil {
+			b.errs = append(b.errs, fmt.Errorf("the glob pattern %q is not valid: %v", p, err))
+			continue
+		}
+		if len(matches) == 0 {
+			b.errs = append(b.errs, fmt.Errorf("the glob pattern %q did not match any files", p))
+			continue
+		}
+		expanded = append(expanded, matches...)
+	}
+
+	for _, p := range expanded {
 		i, err := os.Stat(p)
 		if os.IsNotExist(err) {
 			b.errs = append(b.errs, fmt.Errorf("the path %q does not exist", p))
@@ -138,17 +227,25 @@ func (b *Builder) Path(paths ...string) *Builder {
 			b.errs = append(b.errs, fmt.Errorf("the path %q cannot be accessed: %v", p, err))
 			continue
 		}
+		extensions := b.fileExtensions
+		if len(extensions) == 0 {
+			extensions = []string{".json", ".yaml"}
+		}
 		var visitor Visitor
-		if i.IsDir() {
+		switch {
+		case i.IsDir():
 			b.dir = true
 			visitor = &DirectoryVisitor{
-				Mapper:       b.mapper,
-				Path:         p,
-				Extensions:   []string{".json", ".yaml"},
-				Recursive:    false,
-				IgnoreErrors: b.continueOnError,
+				Mapper:            b.mapper,
+				Path:              p,
+				Extensions:        extensions,
+				Recursive:         b.recursive,
+				IgnoreErrors:      b.continueOnError,
+				IncludeHiddenDirs: b.includeHiddenDirs,
 			}
-		} else {
+		case isJsonnet(p):
+			visitor = b.newJsonnetVisitor(p)
+		default:
 			visitor = &PathVisitor{
 				Mapper:       b.mapper,
 				Path:         p,
@@ -342,6 +439,159 @@ func (b *Builder) SingleResourceType() *Builder {
 	return b
 }
 
+// PageSize sets the number of objects requested per page when listing resources
+// matched by a selector. It has no effect if Chunked(false) has been called. The
+// default, if PageSize is never called, is 500.
+func (b *Builder) PageSize(size int) *Builder {
+	b.pageSize = size
+	return b
+}
+
+// Chunked controls whether the selector branch issues a paginated LIST that streams
+// items to the VisitorFunc as each page arrives (the default) or fetches the full
+// list from the server in one call before visiting anything. Pass false to opt out
+// of pagination, for example against older apiservers that don't support continue
+// tokens.
+func (b *Builder) Chunked(chunked bool) *Builder {
+	b.disablePagination = !chunked
+	return b
+}
+
+// JPath adds directories to the search path used to resolve jsonnet import statements
+// that are not otherwise resolvable relative to the importing file. An entry may be
+// an "https://..." URL prefix, in which case candidates under it are fetched over
+// HTTP rather than read from disk; an import of a full URL is fetched directly and
+// does not consult the search path.
+func (b *Builder) JPath(paths ...string) *Builder {
+	b.jpath = append(b.jpath, paths...)
+	return b
+}
+
+// JsonnetTLA sets the top-level arguments passed to a jsonnet manifest's top-level
+// function, if it has one. Only meaningful for .jsonnet/.libsonnet inputs.
+func (b *Builder) JsonnetTLA(vars map[string]string) *Builder {
+	if b.jsonnetTLA == nil {
+		b.jsonnetTLA = map[string]string{}
+	}
+	for k, v := range vars {
+		b.jsonnetTLA[k] = v
+	}
+	return b
+}
+
+// JsonnetExtVar sets external variables visible to a jsonnet manifest via std.extVar.
+// Only meaningful for .jsonnet/.libsonnet inputs.
+func (b *Builder) JsonnetExtVar(vars map[string]string) *Builder {
+	if b.jsonnetExtVar == nil {
+		b.jsonnetExtVar = map[string]string{}
+	}
+	for k, v := range vars {
+		b.jsonnetExtVar[k] = v
+	}
+	return b
+}
+
+// newSelectorVisitor builds the Visitor used to satisfy a Selector() call. By
+// default it streams results page by page via ChunkedSelector; Chunked(false)
+// falls back to the original NewSelector, which fetches everything in one call.
+func (b *Builder) newSelectorVisitor(client RESTClient, mapping *meta.RESTMapping, namespace string) Visitor {
+	if b.disablePagination {
+		return NewSelector(client, mapping, namespace, b.selector)
+	}
+	pageSize := b.pageSize
+	if pageSize <= 0 {
+		pageSize = 500
+	}
+	return &ChunkedSelector{
+		Client:    client,
+		Mapping:   mapping,
+		Namespace: namespace,
+		Selector:  b.selector,
+		PageSize:  pageSize,
+	}
+}
+
+func (b *Builder) newJsonnetVisitor(path string) Visitor {
+	return &JsonnetVisitor{
+		Mapper:       b.mapper,
+		Path:         path,
+		JPath:        b.jpath,
+		TLAVars:      b.jsonnetTLA,
+		ExtVars:      b.jsonnetExtVar,
+		IgnoreErrors: b.continueOnError,
+	}
+}
+
+// isJsonnet returns true if path has a .jsonnet or .libsonnet extension.
+func isJsonnet(path string) bool {
+	return strings.HasSuffix(path, ".jsonnet") || strings.HasSuffix(path, ".libsonnet")
+}
+
+// Recursive instructs Path to descend into subdirectories of any directory argument,
+// rather than only reading the files directly inside it. Subdirectories are walked in
+// sorted order so output is stable across runs, and directories beginning with "."
+// are skipped unless IncludeHiddenDirectories is set.
+func (b *Builder) Recursive() *Builder {
+	b.recursive = true
+	return b
+}
+
+// FileExtensions overrides the default [".json", ".yaml"] set of file extensions that
+// Path considers when reading a directory.
+func (b *Builder) FileExtensions(exts ...string) *Builder {
+	b.fileExtensions = exts
+	return b
+}
+
+// IncludeHiddenDirectories opts out of the default behavior of skipping "."-prefixed
+// directories when walking a directory argument recursively.
+func (b *Builder) IncludeHiddenDirectories() *Builder {
+	b.includeHiddenDirs = true
+	return b
+}
+
+// globPaths expands a glob pattern into the filesystem paths it matches. The first
+// "**" in the pattern matches zero or more directories at any depth; everything
+// after it (including any later "**", which then acts like "*") is matched with
+// filepath.Match semantics via filepath.Glob.
+func globPaths(pattern string) ([]string, error) {
+	if !strings.Contains(pattern, "**") {
+		return filepath.Glob(pattern)
+	}
+
+	parts := strings.SplitN(pattern, "**", 2)
+	root := strings.TrimSuffix(parts[0], string(filepath.Separator))
+	if root == "" {
+		root = "."
+	}
+	rest := strings.TrimPrefix(parts[1], string(filepath.Separator))
+
+	matches := []string{}
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		candidate := path
+		if rest != "" {
+			candidate = filepath.Join(path, rest)
+		}
+		found, err := filepath.Glob(candidate)
+		if err != nil {
+			return err
+		}
+		matches = append(matches, found...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
 func (b *Builder) resourceMappings() ([]*meta.RESTMapping, error) {
 	if len(b.resources) > 1 && b.singleResourceType {
 		return nil, fmt.Errorf("you may only specify a single resource type")
@@ -387,6 +637,10 @@ func (b *Builder) resourceTupleMappings() (map[string]*meta.RESTMapping, error)
 }
 
 func (b *Builder) visitorResult() *Result {
+	if len(b.clusterMappers) > 0 {
+		return b.multiClusterVisitorResult()
+	}
+
 	if len(b.errs) > 0 {
 		return &Result{err: errors.NewAggregate(b.errs)}
 	}
@@ -429,7 +683,7 @@ func (b *Builder) visitorResult() *Result {
 			if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
 				selectorNamespace = ""
 			}
-			visitors = append(visitors, NewSelector(client, mapping, selectorNamespace, b.selector))
+			visitors = append(visitors, b.newSelectorVisitor(client, mapping, selectorNamespace))
 		}
 		if b.continueOnError {
 			return &Result{visitor: EagerVisitorList(visitors), sources: visitors}
@@ -573,6 +827,61 @@ func (b *Builder) visitorResult() *Result {
 	return &Result{err: fmt.Errorf("you must provide one or more resources by argument or filename")}
 }
 
+// multiClusterVisitorResult runs visitorResult once per cluster set via Clusters(),
+// tags every Info that comes back with its originating cluster name, and aggregates
+// the per-cluster visitors into a single Result. Each cluster resolves its own
+// errors independently, so a mapping failure in one cluster does not prevent the
+// others from being visited when ContinueOnError() is set.
+//
+// Clusters() only fans out the selector, resource/name, and resource-tuple branches
+// of resolution. File, URL, jsonnet, and OCI inputs are not supported together with
+// Clusters(): if any were given, the Result carries an error and nothing is visited.
+func (b *Builder) multiClusterVisitorResult() *Result {
+	if len(b.errs) > 0 {
+		return &Result{err: errors.NewAggregate(b.errs)}
+	}
+	if len(b.paths) > 0 {
+		return &Result{err: fmt.Errorf("Clusters() cannot be combined with file, URL, or OCI input; it only fans out selector, resource/name, and resource tuple resolution across clusters")}
+	}
+
+	visitors := []Visitor{}
+	sources := []Visitor{}
+	singular := len(b.clusterNames) == 1
+	clusterErrs := []error{}
+	for _, name := range b.clusterNames {
+		sub := *b
+		sub.mapper = b.clusterMappers[name]
+		sub.clusterMappers = nil
+		sub.clusterNames = nil
+
+		r := sub.visitorResult()
+		if r.err != nil {
+			clusterErrs = append(clusterErrs, fmt.Errorf("cluster %q: %v", name, r.err))
+			if !b.continueOnError {
+				return &Result{err: errors.NewAggregate(clusterErrs)}
+			}
+			continue
+		}
+		singular = singular && r.singular
+
+		tagged := NewDecoratedVisitor(r.visitor, TagCluster(name))
+		visitors = append(visitors, tagged)
+		sources = append(sources, tagged)
+	}
+
+	if len(visitors) == 0 && len(clusterErrs) > 0 {
+		return &Result{err: errors.NewAggregate(clusterErrs)}
+	}
+
+	var visitor Visitor
+	if b.continueOnError {
+		visitor = EagerVisitorList(visitors)
+	} else {
+		visitor = VisitorList(visitors)
+	}
+	return &Result{singular: singular, visitor: visitor, sources: sources}
+}
+
 // Do returns a Result object with a Visitor for the resources identified by the Builder.
 // The visitor will respect the error behavior specified by ContinueOnError. Note that stream
 // inputs are consumed by the first execution - use Infos() or Object() on the Result to capture a list
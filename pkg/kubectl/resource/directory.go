@@ -0,0 +1,134 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// This file is the package's sole definition of DirectoryVisitor; builder.go only
+// constructs it via struct literal. Do not add a second, competing declaration.
+
+// DirectoryVisitor visits every file directly inside Path whose extension matches
+// Extensions, decoding each one into an Info the same way PathVisitor decodes a
+// single file. If Recursive is set it descends into subdirectories as well, in
+// sorted order so output is stable across runs, skipping "."-prefixed directories
+// unless IncludeHiddenDirs is set. Symlinked directories are followed, but a
+// directory whose resolved (real) path has already been visited - whether reached
+// directly or through a symlink loop - is not visited again.
+type DirectoryVisitor struct {
+	Mapper *Mapper
+
+	Path       string
+	Extensions []string
+	Recursive  bool
+
+	IgnoreErrors      bool
+	IncludeHiddenDirs bool
+}
+
+// Visit implements Visitor.
+func (v *DirectoryVisitor) Visit(fn VisitorFunc) error {
+	return v.visitDir(v.Path, fn, map[string]bool{})
+}
+
+func (v *DirectoryVisitor) visitDir(dir string, fn VisitorFunc, visited map[string]bool) error {
+	real, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return v.handleErr(err)
+	}
+	if visited[real] {
+		return nil
+	}
+	visited[real] = true
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return v.handleErr(err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	for _, entry := range entries {
+		name := entry.Name()
+		full := filepath.Join(dir, name)
+
+		isDir := entry.IsDir()
+		if entry.Mode()&os.ModeSymlink != 0 {
+			if target, err := os.Stat(full); err == nil && target.IsDir() {
+				isDir = true
+			}
+		}
+
+		if isDir {
+			if !v.Recursive {
+				continue
+			}
+			if strings.HasPrefix(name, ".") && !v.IncludeHiddenDirs {
+				continue
+			}
+			if err := v.visitDir(full, fn, visited); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !v.hasMatchingExtension(name) {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(full)
+		if err != nil {
+			if v.IgnoreErrors {
+				continue
+			}
+			return err
+		}
+		info, err := v.Mapper.InfoForData(data, full)
+		if err != nil {
+			if v.IgnoreErrors {
+				continue
+			}
+			return err
+		}
+		if err := fn(info, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *DirectoryVisitor) hasMatchingExtension(name string) bool {
+	ext := filepath.Ext(name)
+	for _, e := range v.Extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *DirectoryVisitor) handleErr(err error) error {
+	if v.IgnoreErrors {
+		return nil
+	}
+	return fmt.Errorf("error walking %q: %v", v.Path, err)
+}
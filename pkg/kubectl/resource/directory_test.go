@@ -0,0 +1,101 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestDirectoryVisitorSymlinkLoop ensures a recursive walk terminates when a
+// subdirectory symlinks back to an ancestor, rather than recursing forever. No
+// file here has a matching extension, so the test exercises only the walk/visited
+// bookkeeping, not object decoding.
+func TestDirectoryVisitorSymlinkLoop(t *testing.T) {
+	root, err := ioutil.TempDir("", "directory-visitor-symlink")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	sub := filepath.Join(root, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// sub/loop -> root, so a naive recursive walk never terminates.
+	if err := os.Symlink(root, filepath.Join(sub, "loop")); err != nil {
+		t.Skipf("symlinks not supported on this platform: %v", err)
+	}
+
+	v := &DirectoryVisitor{
+		Path:       root,
+		Extensions: []string{".yaml"},
+		Recursive:  true,
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- v.Visit(func(info *Info, err error) error { return err })
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Visit did not terminate, symlink loop was not broken")
+	}
+}
+
+// TestDirectoryVisitorContinueOnErrorSkipsUnreadableFile verifies that IgnoreErrors
+// (set from Builder.ContinueOnError) suppresses a file read failure and lets the
+// walk continue, while the non-tolerant mode surfaces the same failure as an error.
+func TestDirectoryVisitorContinueOnErrorSkipsUnreadableFile(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("running as root, file permissions are not enforced")
+	}
+
+	root, err := ioutil.TempDir("", "directory-visitor-continue")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	unreadable := filepath.Join(root, "unreadable.yaml")
+	if err := ioutil.WriteFile(unreadable, []byte("kind: Pod\n"), 0000); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Chmod(unreadable, 0644)
+
+	v := &DirectoryVisitor{
+		Path:         root,
+		Extensions:   []string{".yaml"},
+		IgnoreErrors: true,
+	}
+	if err := v.Visit(func(info *Info, err error) error { return err }); err != nil {
+		t.Fatalf("expected IgnoreErrors to suppress the unreadable file error, got: %v", err)
+	}
+
+	v.IgnoreErrors = false
+	if err := v.Visit(func(info *Info, err error) error { return err }); err == nil {
+		t.Fatalf("expected an error reading the unreadable file when IgnoreErrors is false")
+	}
+}
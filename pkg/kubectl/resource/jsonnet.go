@@ -0,0 +1,292 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+	"gopkg.in/yaml.v2"
+)
+
+// JsonnetVisitor evaluates a .jsonnet or .libsonnet file and decodes the resulting
+// JSON value(s) into runtime.Objects, the same way PathVisitor decodes a YAML or
+// JSON file. The evaluated result may be a single manifest object, an array of
+// manifest objects, or an object whose values are themselves manifest objects (the
+// common "map of named resources" shape); all three are flattened to a stream of
+// individual objects.
+type JsonnetVisitor struct {
+	Mapper *Mapper
+
+	// Path is the file path or URL of the jsonnet entrypoint to evaluate.
+	Path string
+
+	// JPath is an additional list of directories (or URL prefixes) searched when
+	// resolving import statements that aren't found relative to the importing file.
+	JPath []string
+
+	// TLAVars and ExtVars are passed to the VM as top-level arguments and external
+	// variables, respectively, before Path is evaluated.
+	TLAVars map[string]string
+	ExtVars map[string]string
+
+	IgnoreErrors bool
+}
+
+// Visit implements Visitor.
+func (v *JsonnetVisitor) Visit(fn VisitorFunc) error {
+	vm := jsonnet.MakeVM()
+	vm.Importer(newJsonnetImporter(v.JPath))
+	registerJsonnetNativeFuncs(vm)
+	for name, value := range v.TLAVars {
+		vm.TLAVar(name, value)
+	}
+	for name, value := range v.ExtVars {
+		vm.ExtVar(name, value)
+	}
+
+	snippet, err := readJsonnetSource(v.Path)
+	if err != nil {
+		return fmt.Errorf("unable to read %q: %v", v.Path, err)
+	}
+
+	out, err := vm.EvaluateSnippet(v.Path, snippet)
+	if err != nil {
+		return fmt.Errorf("unable to evaluate jsonnet %q: %v", v.Path, err)
+	}
+
+	objects, err := flattenJsonnetOutput(out)
+	if err != nil {
+		return fmt.Errorf("unable to interpret jsonnet output of %q: %v", v.Path, err)
+	}
+
+	for _, raw := range objects {
+		info, err := v.Mapper.InfoForData(raw, v.Path)
+		if err != nil {
+			if v.IgnoreErrors {
+				continue
+			}
+			return err
+		}
+		if err := fn(info, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flattenJsonnetOutput turns a jsonnet evaluation result - a single object, an array
+// of objects, or an object whose values are objects - into a slice of individual
+// JSON-encoded manifests.
+func flattenJsonnetOutput(out string) ([][]byte, error) {
+	var value interface{}
+	if err := json.Unmarshal([]byte(out), &value); err != nil {
+		return nil, err
+	}
+
+	switch t := value.(type) {
+	case []interface{}:
+		result := make([][]byte, 0, len(t))
+		for _, item := range t {
+			raw, err := json.Marshal(item)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, raw)
+		}
+		return result, nil
+	case map[string]interface{}:
+		if isManifestObject(t) {
+			raw, err := json.Marshal(t)
+			if err != nil {
+				return nil, err
+			}
+			return [][]byte{raw}, nil
+		}
+		result := make([][]byte, 0, len(t))
+		for _, item := range t {
+			raw, err := json.Marshal(item)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, raw)
+		}
+		return result, nil
+	default:
+		raw, err := json.Marshal(t)
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{raw}, nil
+	}
+}
+
+// isManifestObject returns true if obj looks like a single Kubernetes manifest
+// (has a "kind" field) rather than a map of named manifests.
+func isManifestObject(obj map[string]interface{}) bool {
+	_, ok := obj["kind"]
+	return ok
+}
+
+func readJsonnetSource(path string) (string, error) {
+	if isURL(path) {
+		resp, err := http.Get(path)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("unexpected status code %d fetching %s", resp.StatusCode, path)
+		}
+		data, err := ioutil.ReadAll(resp.Body)
+		return string(data), err
+	}
+	data, err := ioutil.ReadFile(path)
+	return string(data), err
+}
+
+func isURL(path string) bool {
+	return len(path) > 7 && (path[:7] == "http://" || path[:8] == "https://")
+}
+
+// joinURLPath joins a URL base with a relative import path by plain string
+// concatenation, so it does not go through filepath.Join's Clean step (which would
+// collapse "https://" down to "https:/").
+func joinURLPath(base, rel string) string {
+	if strings.HasSuffix(base, "/") {
+		return base + rel
+	}
+	return base + "/" + rel
+}
+
+// jsonnetImporter resolves import statements against the directory of the importing
+// file, then against each entry of jpath in order. Entries (or the importing file
+// itself) that look like URLs are fetched over HTTP rather than read from disk.
+type jsonnetImporter struct {
+	jpath []string
+	cache map[string]jsonnet.Contents
+}
+
+func newJsonnetImporter(jpath []string) *jsonnetImporter {
+	return &jsonnetImporter{jpath: jpath, cache: map[string]jsonnet.Contents{}}
+}
+
+func (i *jsonnetImporter) Import(importedFrom, importedPath string) (jsonnet.Contents, string, error) {
+	candidates := []string{}
+	if isURL(importedPath) {
+		candidates = append(candidates, importedPath)
+	} else {
+		if isURL(importedFrom) {
+			candidates = append(candidates, importedFrom[:len(importedFrom)-len(filepath.Base(importedFrom))]+importedPath)
+		} else {
+			candidates = append(candidates, filepath.Join(filepath.Dir(importedFrom), importedPath))
+		}
+		for _, dir := range i.jpath {
+			if isURL(dir) {
+				// filepath.Join runs Clean, which collapses the "//" in "https://"
+				// down to a single slash - build URL candidates by concatenation
+				// instead, the same way the isURL(importedFrom) branch above does.
+				candidates = append(candidates, joinURLPath(dir, importedPath))
+				continue
+			}
+			candidates = append(candidates, filepath.Join(dir, importedPath))
+		}
+	}
+
+	for _, candidate := range candidates {
+		if contents, ok := i.cache[candidate]; ok {
+			return contents, candidate, nil
+		}
+		data, err := readJsonnetSource(candidate)
+		if err != nil {
+			continue
+		}
+		contents := jsonnet.MakeContents(data)
+		i.cache[candidate] = contents
+		return contents, candidate, nil
+	}
+	return jsonnet.Contents{}, "", fmt.Errorf("could not find import %q from %q", importedPath, importedFrom)
+}
+
+// registerJsonnetNativeFuncs wires the small set of helpers manifests commonly need
+// to preprocess strings coming from the surrounding environment (parsing embedded
+// YAML/JSON, or building regexes safely) into the VM as native functions.
+func registerJsonnetNativeFuncs(vm *jsonnet.VM) {
+	vm.NativeFunction(&jsonnet.NativeFunction{
+		Name:   "parseJson",
+		Params: ast.Identifiers{"json"},
+		Func: func(args []interface{}) (interface{}, error) {
+			str, ok := args[0].(string)
+			if !ok {
+				return nil, fmt.Errorf("parseJson: expected a string argument, got %T", args[0])
+			}
+			var out interface{}
+			if err := json.Unmarshal([]byte(str), &out); err != nil {
+				return nil, err
+			}
+			return out, nil
+		},
+	})
+	vm.NativeFunction(&jsonnet.NativeFunction{
+		Name:   "parseYaml",
+		Params: ast.Identifiers{"yaml"},
+		Func: func(args []interface{}) (interface{}, error) {
+			str, ok := args[0].(string)
+			if !ok {
+				return nil, fmt.Errorf("parseYaml: expected a string argument, got %T", args[0])
+			}
+			var out interface{}
+			if err := yaml.Unmarshal([]byte(str), &out); err != nil {
+				return nil, err
+			}
+			return out, nil
+		},
+	})
+	vm.NativeFunction(&jsonnet.NativeFunction{
+		Name:   "escapeStringRegex",
+		Params: ast.Identifiers{"str"},
+		Func: func(args []interface{}) (interface{}, error) {
+			str, ok := args[0].(string)
+			if !ok {
+				return nil, fmt.Errorf("escapeStringRegex: expected a string argument, got %T", args[0])
+			}
+			return regexp.QuoteMeta(str), nil
+		},
+	})
+	vm.NativeFunction(&jsonnet.NativeFunction{
+		Name:   "regexMatch",
+		Params: ast.Identifiers{"regex", "str"},
+		Func: func(args []interface{}) (interface{}, error) {
+			regex, ok := args[0].(string)
+			if !ok {
+				return nil, fmt.Errorf("regexMatch: expected a string regex argument, got %T", args[0])
+			}
+			str, ok := args[1].(string)
+			if !ok {
+				return nil, fmt.Errorf("regexMatch: expected a string argument, got %T", args[1])
+			}
+			return regexp.MatchString(regex, str)
+		},
+	})
+}
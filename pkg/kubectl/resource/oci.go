@@ -0,0 +1,438 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ociChallengeParam matches a single key="value" pair inside a WWW-Authenticate
+// header, e.g. realm="https://auth.example.com/token".
+var ociChallengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+const (
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	ociIndexMediaType    = "application/vnd.oci.image.index.v1+json"
+)
+
+// OCIVisitor fetches Kubernetes manifests packaged as OCI artifacts from a
+// container registry, unpacks their tarball layers, and decodes the contained
+// YAML/JSON documents the same way StreamVisitor decodes a stream. A reference
+// that resolves to an index is treated as a manifest list: every manifest it
+// references is fetched and visited in turn, so a single ref can represent a
+// base manifest plus overlays.
+type OCIVisitor struct {
+	Mapper *Mapper
+
+	// Ref is an OCI reference such as "registry.example.com/team/app-manifests:v1.2.3"
+	// or "...@sha256:...". The "oci://" scheme prefix, if any, has already been
+	// stripped by the caller.
+	Ref string
+
+	IgnoreErrors bool
+}
+
+// Visit implements Visitor. It re-resolves Ref against the registry every time it
+// is called, so a mutable tag is always read fresh.
+func (v *OCIVisitor) Visit(fn VisitorFunc) error {
+	registry, repository, reference, err := splitOCIRef(v.Ref)
+	if err != nil {
+		return err
+	}
+	client := &ociClient{registry: registry, repository: repository, auth: loadOCIAuth(registry)}
+
+	manifests, err := client.resolveManifests(reference)
+	if err != nil {
+		return fmt.Errorf("unable to resolve OCI reference %q: %v", v.Ref, err)
+	}
+
+	for _, manifest := range manifests {
+		objects, err := client.fetchManifestObjects(manifest)
+		if err != nil {
+			if v.IgnoreErrors {
+				continue
+			}
+			return fmt.Errorf("unable to fetch OCI artifact %q: %v", v.Ref, err)
+		}
+		for _, raw := range objects {
+			stream := NewStreamVisitor(bytes.NewReader(raw), v.Mapper, v.Ref, v.IgnoreErrors)
+			if err := stream.Visit(fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ociDescriptor is the subset of an OCI content descriptor this visitor needs.
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+
+	// data holds the manifest bytes already fetched while resolving this
+	// descriptor, if any, so fetchManifestObjects can skip re-requesting
+	// them. It is never populated from JSON - only resolveManifests sets it.
+	data []byte
+}
+
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Config    ociDescriptor   `json:"config"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+type ociIndex struct {
+	MediaType string          `json:"mediaType"`
+	Manifests []ociDescriptor `json:"manifests"`
+}
+
+// ociClient is a minimal OCI Distribution client - just enough to resolve a
+// reference to one or more manifests and read their layer blobs.
+type ociClient struct {
+	registry   string
+	repository string
+	auth       string // "user:pass", base64-ready, empty if anonymous
+	bearer     string // token obtained from a prior WWW-Authenticate challenge, if any
+}
+
+// resolveManifests resolves reference to the list of manifest descriptors a caller
+// should fetch. A single-manifest reference resolves to itself; an index resolves
+// to every manifest it lists (the "referrers/manifest-list" flow).
+func (c *ociClient) resolveManifests(reference string) ([]ociDescriptor, error) {
+	data, mediaType, err := c.getManifest(reference)
+	if err != nil {
+		return nil, err
+	}
+	if mediaType == ociIndexMediaType {
+		var index ociIndex
+		if err := json.Unmarshal(data, &index); err != nil {
+			return nil, err
+		}
+		return index.Manifests, nil
+	}
+	return []ociDescriptor{{MediaType: mediaType, Digest: reference, data: data}}, nil
+}
+
+// fetchManifestObjects fetches every layer of the manifest referenced by d, untars
+// each, and returns the raw bytes of every YAML/JSON file found inside. If d carries
+// data already fetched by resolveManifests (the common non-index case), that is used
+// directly instead of re-requesting the same digest from the registry.
+func (c *ociClient) fetchManifestObjects(d ociDescriptor) ([][]byte, error) {
+	data := d.data
+	if data == nil {
+		fetched, _, err := c.getManifest(d.Digest)
+		if err != nil {
+			return nil, err
+		}
+		data = fetched
+	}
+	var manifest ociManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+
+	objects := [][]byte{}
+	for _, layer := range manifest.Layers {
+		blob, err := c.getBlob(layer.Digest)
+		if err != nil {
+			return nil, err
+		}
+		files, err := untarManifests(blob)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, files...)
+	}
+	return objects, nil
+}
+
+func (c *ociClient) getManifest(reference string) ([]byte, string, error) {
+	url := fmt.Sprintf("https://%s/v2/%s/manifests/%s", c.registry, c.repository, reference)
+	resp, err := c.do("GET", url, map[string]string{
+		"Accept": strings.Join([]string{ociManifestMediaType, ociIndexMediaType}, ", "),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("registry returned %s for manifest %s", resp.Status, reference)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
+
+func (c *ociClient) getBlob(digest string) ([]byte, error) {
+	url := fmt.Sprintf("https://%s/v2/%s/blobs/%s", c.registry, c.repository, digest)
+	resp, err := c.do("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("registry returned %s for blob %s", resp.Status, digest)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// do issues a request against the registry, transparently handling the OAuth2
+// bearer-token challenge flow that GHCR, ECR, and most non-trivial Harbor
+// deployments require (including for anonymous pulls): the first request is tried
+// with whatever credentials are already cached, and a 401 response carrying a
+// "WWW-Authenticate: Bearer ..." challenge triggers a token exchange against the
+// advertised realm before the request is retried once with the resulting token. A
+// token obtained this way is cached on the client and reused for later requests.
+func (c *ociClient) do(method, url string, headers map[string]string) (*http.Response, error) {
+	build := func() (*http.Request, error) {
+		req, err := http.NewRequest(method, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		return req, nil
+	}
+
+	req, err := build()
+	if err != nil {
+		return nil, err
+	}
+	c.setAuth(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+
+	token, err := c.exchangeToken(challenge)
+	if err != nil {
+		return nil, fmt.Errorf("registry authentication failed: %v", err)
+	}
+	c.bearer = token
+
+	retry, err := build()
+	if err != nil {
+		return nil, err
+	}
+	retry.Header.Set("Authorization", "Bearer "+token)
+	return http.DefaultClient.Do(retry)
+}
+
+func (c *ociClient) setAuth(req *http.Request) {
+	switch {
+	case c.bearer != "":
+		req.Header.Set("Authorization", "Bearer "+c.bearer)
+	case c.auth != "":
+		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(c.auth)))
+	}
+}
+
+// exchangeToken parses a "Bearer realm=\"...\",service=\"...\",scope=\"...\""
+// WWW-Authenticate challenge and performs the token exchange it describes,
+// returning the bearer token to use for subsequent requests. Static credentials
+// (if any) are sent to the token endpoint as HTTP basic auth; an anonymous pull
+// omits them entirely, which registries that allow public reads still honor.
+func (c *ociClient) exchangeToken(challenge string) (string, error) {
+	if !strings.HasPrefix(challenge, "Bearer ") {
+		return "", fmt.Errorf("unsupported authentication challenge %q", challenge)
+	}
+	params := map[string]string{}
+	for _, m := range ociChallengeParam.FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("authentication challenge %q is missing a realm", challenge)
+	}
+
+	query := url.Values{}
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	if scope := params["scope"]; scope != "" {
+		query.Set("scope", scope)
+	}
+	tokenURL := realm
+	if len(query) > 0 {
+		tokenURL += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest("GET", tokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	if c.auth != "" {
+		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(c.auth)))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint %q returned %s", realm, resp.Status)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	var tokenResp struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(data, &tokenResp); err != nil {
+		return "", err
+	}
+	if tokenResp.Token != "" {
+		return tokenResp.Token, nil
+	}
+	if tokenResp.AccessToken != "" {
+		return tokenResp.AccessToken, nil
+	}
+	return "", fmt.Errorf("token endpoint %q response did not contain a token", realm)
+}
+
+// splitOCIRef splits a reference of the form host[:port]/repo/path[:tag|@digest]
+// into its registry, repository, and tag-or-digest components.
+func splitOCIRef(ref string) (registry, repository, reference string, err error) {
+	slash := strings.Index(ref, "/")
+	if slash < 0 {
+		return "", "", "", fmt.Errorf("OCI reference %q is missing a repository path", ref)
+	}
+	registry = ref[:slash]
+	rest := ref[slash+1:]
+
+	if at := strings.LastIndex(rest, "@"); at >= 0 {
+		return registry, rest[:at], rest[at+1:], nil
+	}
+	if colon := strings.LastIndex(rest, ":"); colon >= 0 && !strings.Contains(rest[colon:], "/") {
+		return registry, rest[:colon], rest[colon+1:], nil
+	}
+	return registry, rest, "latest", nil
+}
+
+// untarManifests reads a (possibly gzip-compressed) tar stream and returns the raw
+// contents of every .yaml/.yml/.json file it contains.
+func untarManifests(blob []byte) ([][]byte, error) {
+	var r io.Reader = &byteReader{b: blob}
+	if gz, err := gzip.NewReader(&byteReader{b: blob}); err == nil {
+		r = gz
+		defer gz.Close()
+	}
+
+	tr := tar.NewReader(r)
+	files := [][]byte{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		ext := filepath.Ext(hdr.Name)
+		if ext != ".yaml" && ext != ".yml" && ext != ".json" {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, data)
+	}
+	return files, nil
+}
+
+type byteReader struct {
+	b []byte
+	i int
+}
+
+func (r *byteReader) Read(p []byte) (int, error) {
+	if r.i >= len(r.b) {
+		return 0, io.EOF
+	}
+	n := copy(p, r.b[r.i:])
+	r.i += n
+	return n, nil
+}
+
+// loadOCIAuth looks up credentials for registry the way docker/podman do: first
+// ~/.docker/config.json, then /etc/containers/auth.json. It returns a "user:pass"
+// string suitable for HTTP basic auth, or "" if no matching entry was found.
+func loadOCIAuth(registry string) string {
+	home, err := os.UserHomeDir()
+	if err == nil {
+		if auth, ok := readOCIAuthFile(filepath.Join(home, ".docker", "config.json"), registry); ok {
+			return auth
+		}
+	}
+	if auth, ok := readOCIAuthFile("/etc/containers/auth.json", registry); ok {
+		return auth
+	}
+	return ""
+}
+
+func readOCIAuthFile(path, registry string) (string, bool) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	var config struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "", false
+	}
+	entry, ok := config.Auths[registry]
+	if !ok {
+		return "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+	if err != nil {
+		return "", false
+	}
+	return string(decoded), true
+}
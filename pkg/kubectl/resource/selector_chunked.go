@@ -0,0 +1,137 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/errors"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/meta"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+)
+
+// ChunkedSelector is a Visitor like Selector, but instead of fetching the entire
+// matching list in one call it issues a series of limit/continue paginated LIST
+// requests and streams each page's items to the VisitorFunc as soon as they arrive.
+// This keeps memory bounded and lets the first callback fire well before a large
+// list has been fully retrieved. Callers that need the full set aggregated in
+// memory should still wrap the result in EagerVisitorList.
+type ChunkedSelector struct {
+	Client    RESTClient
+	Mapping   *meta.RESTMapping
+	Namespace string
+	Selector  labels.Selector
+	PageSize  int
+}
+
+// partialList is enough of a List object's shape to drive pagination and to split
+// it into individual items without depending on the concrete list type.
+type partialList struct {
+	Items    []json.RawMessage `json:"items"`
+	Metadata struct {
+		Continue string `json:"continue"`
+	} `json:"metadata"`
+}
+
+// partialItemMeta pulls the name and namespace out of an item so an Info can be
+// built for it without fully decoding the object first. Namespace matters even
+// though the list itself was scoped by s.Namespace: a cluster-wide query (s.Namespace
+// == "") returns items from many different namespaces, and each Info needs its own.
+type partialItemMeta struct {
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+}
+
+// Visit implements Visitor.
+func (s *ChunkedSelector) Visit(fn VisitorFunc) error {
+	namespaced := s.Mapping.Scope.Name() == meta.RESTScopeNameNamespace
+	limit := s.PageSize
+	continueToken := ""
+	// emitted guards against visiting the same item twice, which would otherwise
+	// happen if the 410-Gone fallback below re-lists from the beginning after a
+	// continue token expires on page 2 or later.
+	emitted := map[string]bool{}
+
+	for {
+		list, err := s.listPage(namespaced, limit, continueToken)
+		if err != nil {
+			// The continue token can be invalidated by a compaction on the server
+			// between pages; fall back to an unpaginated list rather than failing
+			// outright.
+			if errors.IsResourceExpired(err) && continueToken != "" {
+				limit = 0
+				continueToken = ""
+				list, err = s.listPage(namespaced, limit, continueToken)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, raw := range list.Items {
+			var itemMeta partialItemMeta
+			if err := json.Unmarshal(raw, &itemMeta); err != nil {
+				return err
+			}
+			key := itemMeta.Metadata.Namespace + "/" + itemMeta.Metadata.Name
+			if emitted[key] {
+				continue
+			}
+			obj, err := s.Mapping.Codec.Decode(raw)
+			if err != nil {
+				return err
+			}
+			info := NewInfo(s.Client, s.Mapping, itemMeta.Metadata.Namespace, itemMeta.Metadata.Name)
+			info.Object = obj
+			if err := fn(info, nil); err != nil {
+				return err
+			}
+			emitted[key] = true
+		}
+
+		if list.Metadata.Continue == "" {
+			return nil
+		}
+		continueToken = list.Metadata.Continue
+	}
+}
+
+func (s *ChunkedSelector) listPage(namespaced bool, limit int, continueToken string) (*partialList, error) {
+	req := s.Client.Get().
+		NamespaceIfScoped(s.Namespace, namespaced).
+		Resource(s.Mapping.Resource).
+		LabelsSelectorParam(s.Selector)
+	if limit > 0 {
+		req = req.Param("limit", strconv.Itoa(limit))
+	}
+	if continueToken != "" {
+		req = req.Param("continue", continueToken)
+	}
+
+	raw, err := req.Do().Raw()
+	if err != nil {
+		return nil, err
+	}
+	list := &partialList{}
+	if err := json.Unmarshal(raw, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/meta"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/runtime"
+)
+
+// This file is the package's sole definition of VisitorFunc, Visitor, Info, and
+// NewInfo - every other file in this package (builder.go included) only consumes
+// them. Do not add a second, competing declaration of any of these elsewhere.
+
+// VisitorFunc is invoked by a Visitor for each Info it produces. err is non-nil if
+// the Visitor encountered a problem obtaining this Info; implementations that want
+// ContinueOnError-style tolerance should check it before using the Info.
+type VisitorFunc func(*Info, error) error
+
+// Visitor walks a set of resources, calling fn once per resource found.
+type Visitor interface {
+	Visit(fn VisitorFunc) error
+}
+
+// Info holds the information necessary to locate and operate on a single API
+// resource, plus the decoded object once it has been loaded.
+type Info struct {
+	Client  RESTClient
+	Mapping *meta.RESTMapping
+
+	Namespace string
+	Name      string
+
+	Object          runtime.Object
+	ResourceVersion string
+
+	// Cluster is the name of the cluster this Info was resolved against, as set by
+	// Builder.Clusters(). It is empty for Infos resolved against the single default
+	// mapper passed to NewBuilder.
+	Cluster string
+}
+
+// NewInfo returns a new Info identifying a single resource.
+func NewInfo(client RESTClient, mapping *meta.RESTMapping, namespace, name string) *Info {
+	return &Info{
+		Client:    client,
+		Mapping:   mapping,
+		Namespace: namespace,
+		Name:      name,
+	}
+}
+
+// Get retrieves the object for this Info from the server and stores it on Object.
+func (i *Info) Get() error {
+	req := i.Client.Get().
+		NamespaceIfScoped(i.Namespace, i.Mapping.Scope.Name() == meta.RESTScopeNameNamespace).
+		Resource(i.Mapping.Resource).
+		Name(i.Name)
+	raw, err := req.Do().Raw()
+	if err != nil {
+		return err
+	}
+	obj, err := i.Mapping.Codec.Decode(raw)
+	if err != nil {
+		return err
+	}
+	i.Object = obj
+	return nil
+}